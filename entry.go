@@ -0,0 +1,92 @@
+package ipset
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Well-known IP protocol numbers used when parsing a "proto:port" entry
+// component. Defined locally rather than imported so entry parsing works
+// the same on every platform, not just where golang.org/x/sys/unix builds.
+const (
+	protoTCP uint8 = 6
+	protoUDP uint8 = 17
+)
+
+// parseEntry parses the string form of a set member accepted by Add,
+// Delete, Test and the Members of a parsed ListSet/List result
+// ("10.0.0.0/24", "1.2.3.4,tcp:80", "1.2.3.4,tcp:80,10.0.0.1") into a
+// structured IPSetEntry. It covers the member formats used by the common
+// hash:ip, hash:net, hash:ip,port, hash:ip,port,ip and hash:ip,port,net
+// set types.
+func parseEntry(s string) (IPSetEntry, error) {
+	var entry IPSetEntry
+
+	parts := strings.Split(s, ",")
+
+	ip, cidr, err := parseIPOrCIDR(parts[0])
+	if err != nil {
+		return entry, err
+	}
+	entry.IP = ip
+	entry.CIDR = cidr
+
+	if len(parts) > 1 {
+		proto, port, err := parsePort(parts[1])
+		if err != nil {
+			return entry, err
+		}
+		entry.Protocol = proto
+		entry.Port = port
+	}
+
+	if len(parts) > 2 {
+		ip2, cidr2, err := parseIPOrCIDR(parts[2])
+		if err != nil {
+			return entry, err
+		}
+		entry.IP2 = ip2
+		entry.CIDR2 = cidr2
+	}
+
+	return entry, nil
+}
+
+func parseIPOrCIDR(s string) (net.IP, uint8, error) {
+	if strings.Contains(s, "/") {
+		ip, ipNet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, 0, fmt.Errorf("invalid CIDR %q: %w", s, err)
+		}
+		ones, _ := ipNet.Mask.Size()
+		return ip, uint8(ones), nil
+	}
+	ip := net.ParseIP(s)
+	if ip == nil {
+		return nil, 0, fmt.Errorf("invalid IP %q", s)
+	}
+	return ip, 0, nil
+}
+
+func parsePort(s string) (proto uint8, port uint16, err error) {
+	proto = protoTCP
+	portStr := s
+	if i := strings.Index(s, ":"); i >= 0 {
+		switch s[:i] {
+		case "tcp":
+			proto = protoTCP
+		case "udp":
+			proto = protoUDP
+		default:
+			return 0, 0, fmt.Errorf("unsupported protocol %q", s[:i])
+		}
+		portStr = s[i+1:]
+	}
+	p, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q: %w", s, err)
+	}
+	return proto, uint16(p), nil
+}
@@ -0,0 +1,89 @@
+package ipset
+
+import (
+	"net"
+	"testing"
+)
+
+func mustParseIP(s string) net.IP {
+	ip := net.ParseIP(s)
+	if ip == nil {
+		panic("invalid IP in test: " + s)
+	}
+	return ip
+}
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    IPSetEntry
+		wantErr bool
+	}{
+		{
+			name: "bare IP",
+			s:    "10.0.0.1",
+			want: IPSetEntry{IP: mustParseIP("10.0.0.1")},
+		},
+		{
+			name: "CIDR",
+			s:    "10.0.0.0/24",
+			want: IPSetEntry{IP: mustParseIP("10.0.0.0"), CIDR: 24},
+		},
+		{
+			name: "IP and TCP port",
+			s:    "1.2.3.4,tcp:80",
+			want: IPSetEntry{IP: mustParseIP("1.2.3.4"), Protocol: protoTCP, Port: 80},
+		},
+		{
+			name: "IP and UDP port",
+			s:    "1.2.3.4,udp:53",
+			want: IPSetEntry{IP: mustParseIP("1.2.3.4"), Protocol: protoUDP, Port: 53},
+		},
+		{
+			name: "port with no protocol prefix defaults to tcp",
+			s:    "1.2.3.4,80",
+			want: IPSetEntry{IP: mustParseIP("1.2.3.4"), Protocol: protoTCP, Port: 80},
+		},
+		{
+			name: "IP, port and second IP",
+			s:    "1.2.3.4,tcp:80,10.0.0.1",
+			want: IPSetEntry{IP: mustParseIP("1.2.3.4"), Protocol: protoTCP, Port: 80, IP2: mustParseIP("10.0.0.1")},
+		},
+		{
+			name: "IP, port and second IP as CIDR",
+			s:    "1.2.3.4,tcp:80,10.0.0.0/24",
+			want: IPSetEntry{IP: mustParseIP("1.2.3.4"), Protocol: protoTCP, Port: 80, IP2: mustParseIP("10.0.0.0"), CIDR2: 24},
+		},
+		{
+			name:    "invalid IP",
+			s:       "not-an-ip",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported protocol",
+			s:       "1.2.3.4,sctp:80",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseEntry(tt.s)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseEntry(%q): %v", tt.s, err)
+			}
+			if got.IP.String() != tt.want.IP.String() || got.CIDR != tt.want.CIDR ||
+				got.Protocol != tt.want.Protocol || got.Port != tt.want.Port ||
+				got.CIDR2 != tt.want.CIDR2 || !got.IP2.Equal(tt.want.IP2) {
+				t.Errorf("parseEntry(%q) = %+v, want %+v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
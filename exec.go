@@ -0,0 +1,47 @@
+package ipset
+
+import (
+	"io"
+	"os/exec"
+)
+
+// Exec abstracts process execution so IPSet can be unit tested without the
+// real ipset binary installed. It mirrors the relevant part of
+// k8s.io/utils/exec.Interface, scoped down to what this package needs.
+type Exec interface {
+	// Command returns a Cmd that will invoke the named command with args.
+	Command(cmd string, args ...string) Cmd
+	// LookPath searches for an executable named file, as exec.LookPath does.
+	LookPath(file string) (string, error)
+}
+
+// Cmd abstracts a single command invocation.
+type Cmd interface {
+	SetStdin(in io.Reader)
+	SetStdout(out io.Writer)
+	SetStderr(out io.Writer)
+	Run() error
+}
+
+// DefaultExec is the Exec implementation backed by os/exec, used by New()
+// and by NewWithExec(nil).
+var DefaultExec Exec = execExec{}
+
+type execExec struct{}
+
+func (execExec) Command(cmd string, args ...string) Cmd {
+	return &execCmd{cmd: exec.Command(cmd, args...)}
+}
+
+func (execExec) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+type execCmd struct {
+	cmd *exec.Cmd
+}
+
+func (c *execCmd) SetStdin(in io.Reader)   { c.cmd.Stdin = in }
+func (c *execCmd) SetStdout(out io.Writer) { c.cmd.Stdout = out }
+func (c *execCmd) SetStderr(out io.Writer) { c.cmd.Stderr = out }
+func (c *execCmd) Run() error              { return c.cmd.Run() }
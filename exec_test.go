@@ -0,0 +1,65 @@
+package ipset
+
+import (
+	"bytes"
+	"io"
+)
+
+// fakeExec is a test double for Exec that returns a fixed sequence of
+// fakeCmd results, one per call to Command, in order. It lets tests drive
+// IPSet without the real ipset binary.
+type fakeExec struct {
+	cmds []*fakeCmd
+	i    int
+
+	// calls records the args passed to each Command call, for assertions.
+	calls [][]string
+}
+
+func (e *fakeExec) Command(cmd string, args ...string) Cmd {
+	e.calls = append(e.calls, args)
+	if e.i >= len(e.cmds) {
+		return &fakeCmd{}
+	}
+	c := e.cmds[e.i]
+	e.i++
+	return c
+}
+
+func (e *fakeExec) LookPath(file string) (string, error) {
+	return "/usr/sbin/" + file, nil
+}
+
+// fakeCmd is a single scripted Cmd result.
+type fakeCmd struct {
+	stdout string
+	stderr string
+	err    error
+
+	// stdin captures whatever was piped in via SetStdin; the reader is
+	// drained as soon as SetStdin is called.
+	stdin []byte
+}
+
+func (c *fakeCmd) SetStdin(in io.Reader) {
+	if in == nil {
+		return
+	}
+	c.stdin, _ = io.ReadAll(in)
+}
+
+func (c *fakeCmd) SetStdout(out io.Writer) {
+	if out != nil {
+		io.Copy(out, bytes.NewBufferString(c.stdout))
+	}
+}
+
+func (c *fakeCmd) SetStderr(out io.Writer) {
+	if out != nil {
+		io.Copy(out, bytes.NewBufferString(c.stderr))
+	}
+}
+
+func (c *fakeCmd) Run() error {
+	return c.err
+}
@@ -0,0 +1,58 @@
+package ipset
+
+import "strings"
+
+// CreateIPv6 creates a new ipset with the given name and type in the inet6
+// family, ignoring an already-existing set of the same name and type
+// (-exist). Equivalent to Create(name, typ, "family", "inet6", "-exist",
+// ...options).
+func (set *IPSet) CreateIPv6(name string, typ string, options ...string) error {
+	return set.Create(name, typ, append([]string{"family", FamilyInet6, "-exist"}, options...)...)
+}
+
+// ListSets returns the names of every set currently defined, via
+// `ipset list -n`.
+func (set *IPSet) ListSets() ([]string, error) {
+	out, err := set.run("list", "-n")
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, line := range strings.Split(out.String(), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		names = append(names, line)
+	}
+	return names, nil
+}
+
+// SetExists reports whether a set with the given name currently exists.
+func (set *IPSet) SetExists(name string) (bool, error) {
+	names, err := set.ListSets()
+	if err != nil {
+		return false, err
+	}
+	for _, n := range names {
+		if n == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// EntryExists reports whether entry is a member of the named set. Unlike
+// Test, it distinguishes "not a member" from other failures (such as the
+// set not existing), which are returned as an error.
+func (set *IPSet) EntryExists(name string, entry string) (bool, error) {
+	err := set.Test(name, entry)
+	if err == nil {
+		return true, nil
+	}
+	if strings.Contains(err.Error(), "is NOT in set") {
+		return false, nil
+	}
+	return false, err
+}
@@ -6,22 +6,120 @@ package ipset
 import (
 	"bytes"
 	"errors"
-	"os/exec"
+	"fmt"
+	"net"
 	"strings"
 )
 
+// ErrSetNotExist is returned (wrapped) by the exec-based IPSet's methods
+// when the ipset binary reports that the targeted set does not exist,
+// distinguishing that case from other ipset failures. Check for it with
+// errors.Is. Apply and the Netlink backend do not return it.
+var ErrSetNotExist = errors.New("ipset: set does not exist")
+
+// Interface is the common set of operations implemented by every IPSet
+// backend. The exec-based IPSet shells out to the ipset binary; Netlink
+// talks to the kernel directly over netlink sockets. Callers that only need
+// basic CRUD operations can depend on Interface and pick a backend at
+// construction time.
+type Interface interface {
+	Create(name string, typ string, options ...string) error
+	Add(name string, entry string, options ...string) error
+	AddUnique(name string, entry string, options ...string) error
+	Delete(name string, entry string, options ...string) error
+	Test(name string, entry string, options ...string) error
+	Destroy(name string) error
+	Flush(name string) error
+	Rename(from string, to string) error
+	Swap(from string, to string) error
+}
+
+var _ Interface = (*IPSet)(nil)
+
+// Family identifies the address family a set stores.
+const (
+	FamilyInet  = "inet"
+	FamilyInet6 = "inet6"
+)
+
+// IPSetEntry describes a single member of a set, as returned by
+// (*Netlink).List or (*IPSet).ListSet.
+//
+// From ListSet, Entry always holds the member's raw string form, and IP,
+// CIDR, IP2, CIDR2, Port and Protocol are populated for the member formats
+// parseEntry understands (hash:ip, hash:net, hash:ip,port, and their *,ip /
+// *,net variants). For set types with other member formats, such as
+// hash:mac, bitmap:port and list:set, only Entry and the trailing options
+// (Comment, Timeout, Packets, Bytes) are populated. ListSet never sets MAC,
+// IFace or Mark.
+//
+// From (*Netlink).List, Entry is left empty and the typed fields are
+// filled from the attributes the kernel returns.
+type IPSetEntry struct {
+	Entry    string
+	IP       net.IP
+	CIDR     uint8
+	IP2      net.IP
+	CIDR2    uint8
+	Port     uint16
+	Protocol uint8
+	MAC      net.HardwareAddr
+	IFace    string
+	Mark     uint32
+	Comment  string
+	Timeout  uint32
+	Packets  uint64
+	Bytes    uint64
+}
+
+// IPSetInfo is the structured result of listing a set: its header fields
+// plus every member entry.
+type IPSetInfo struct {
+	SetName    string
+	TypeName   string
+	Family     string
+	Revision   uint8
+	HashSize   uint32
+	MaxElem    uint32
+	NumEntries uint32
+	MemSize    uint32
+	References uint32
+	Comment    string
+	Timeout    uint32
+	Netmask    uint8
+	Range      string
+	Counters   bool
+	Comments   bool
+	SkbInfo    bool
+	Entries    []IPSetEntry
+}
+
 type IPSet struct {
 	Path    string
 	Options []string
+	Exec    Exec
 }
 
+// New returns an IPSet that shells out to the real ipset binary found on
+// PATH.
 func New() (*IPSet, error) {
-	binPath, err := exec.LookPath("ipset")
+	return NewWithExec(DefaultExec)
+}
+
+// NewWithExec returns an IPSet that runs commands through execIface instead
+// of the real ipset binary, so callers can substitute a fake in tests. A
+// nil execIface is equivalent to DefaultExec.
+func NewWithExec(execIface Exec) (*IPSet, error) {
+	if execIface == nil {
+		execIface = DefaultExec
+	}
+
+	binPath, err := execIface.LookPath("ipset")
 	if err != nil {
 		return nil, err
 	}
 
-	return &IPSet{binPath, []string{}}, nil
+	return &IPSet{Path: binPath, Options: []string{}, Exec: execIface}, nil
 }
 
 // Create creates a new ipset with a given name and type.
@@ -125,16 +223,25 @@ func (set *IPSet) List(name string) ([]string, error) {
 
 func (set *IPSet) run(args ...string) (*bytes.Buffer, error) {
 	var stderr, stdout bytes.Buffer
-	cmd := exec.Cmd{
-		Path:   set.Path,
-		Args:   append([]string{set.Path}, args...),
-		Stderr: &stderr,
-		Stdout: &stdout,
-	}
+	cmd := set.exec().Command(set.Path, args...)
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
 
 	if err := cmd.Run(); err != nil {
-		return &stdout, errors.New(stderr.String())
+		msg := strings.TrimSpace(stderr.String())
+		wrapped := fmt.Errorf("ipset %s: %w: %s", args[0], err, msg)
+		if strings.Contains(msg, "does not exist") {
+			wrapped = fmt.Errorf("%w: %w", wrapped, ErrSetNotExist)
+		}
+		return &stdout, wrapped
 	}
 
 	return &stdout, nil
 }
+
+func (set *IPSet) exec() Exec {
+	if set.Exec != nil {
+		return set.Exec
+	}
+	return DefaultExec
+}
@@ -0,0 +1,86 @@
+package ipset
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewWithExec(t *testing.T) {
+	exec := &fakeExec{}
+	set, err := NewWithExec(exec)
+	if err != nil {
+		t.Fatalf("NewWithExec: %v", err)
+	}
+	if set.Path != "/usr/sbin/ipset" {
+		t.Errorf("Path = %q, want /usr/sbin/ipset", set.Path)
+	}
+	if set.Exec != exec {
+		t.Errorf("Exec not set to the injected fake")
+	}
+}
+
+func TestNewWithExecNilUsesDefault(t *testing.T) {
+	set, err := NewWithExec(nil)
+	if err != nil {
+		// The real ipset binary need not be installed in the test
+		// environment; NewWithExec(nil) is only expected to fall back to
+		// DefaultExec, which LookPath may or may not resolve here.
+		return
+	}
+	if set.Exec != DefaultExec {
+		t.Errorf("Exec = %v, want DefaultExec", set.Exec)
+	}
+}
+
+func TestRunWrapsError(t *testing.T) {
+	exec := &fakeExec{
+		cmds: []*fakeCmd{
+			{stderr: "ipset v7.1: Kernel error received: Invalid argument", err: errors.New("exit status 1")},
+		},
+	}
+	set := &IPSet{Path: "ipset", Exec: exec}
+
+	err := set.Create("myset", "hash:ip")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if got, want := err.Error(), "ipset create: exit status 1: ipset v7.1: Kernel error received: Invalid argument"; got != want {
+		t.Errorf("error = %q, want %q", got, want)
+	}
+	if errors.Is(err, ErrSetNotExist) {
+		t.Errorf("err should not be ErrSetNotExist")
+	}
+}
+
+func TestRunWrapsErrSetNotExist(t *testing.T) {
+	exec := &fakeExec{
+		cmds: []*fakeCmd{
+			{stderr: "ipset v7.1: The set with the given name does not exist", err: errors.New("exit status 1")},
+		},
+	}
+	set := &IPSet{Path: "ipset", Exec: exec}
+
+	err := set.Destroy("myset")
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if !errors.Is(err, ErrSetNotExist) {
+		t.Errorf("errors.Is(err, ErrSetNotExist) = false, want true; err = %v", err)
+	}
+}
+
+func TestRunNoError(t *testing.T) {
+	exec := &fakeExec{
+		cmds: []*fakeCmd{
+			{stdout: "ok"},
+		},
+	}
+	set := &IPSet{Path: "ipset", Exec: exec}
+
+	if err := set.Flush("myset"); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if len(exec.calls) != 1 || exec.calls[0][0] != "flush" || exec.calls[0][1] != "myset" {
+		t.Errorf("unexpected call args: %v", exec.calls)
+	}
+}
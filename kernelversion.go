@@ -0,0 +1,41 @@
+package ipset
+
+import (
+	"strconv"
+	"strings"
+)
+
+// KernelVersion returns the running kernel's release string (e.g.
+// "4.9.0-8-amd64"), or an empty string if it cannot be determined, such as
+// on non-Linux platforms.
+func KernelVersion() string {
+	return kernelVersion()
+}
+
+// kernelAffectedByDeleteBug reports whether the running kernel falls in the
+// 4.2-4.10 range affected by the ipset del bug described in netfilter
+// bugzilla #1119, where a single del can remove more than the targeted
+// entry.
+func kernelAffectedByDeleteBug() bool {
+	major, minor, ok := parseKernelVersion(KernelVersion())
+	if !ok {
+		return false
+	}
+	return major == 4 && minor >= 2 && minor <= 10
+}
+
+func parseKernelVersion(release string) (major, minor int, ok bool) {
+	fields := strings.SplitN(release, ".", 3)
+	if len(fields) < 2 {
+		return 0, 0, false
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, false
+	}
+	minor, err = strconv.Atoi(fields[1])
+	if err != nil {
+		return 0, 0, false
+	}
+	return major, minor, true
+}
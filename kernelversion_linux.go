@@ -0,0 +1,24 @@
+//go:build linux
+
+package ipset
+
+import (
+	"bytes"
+
+	"golang.org/x/sys/unix"
+)
+
+func kernelVersion() string {
+	var uts unix.Utsname
+	if err := unix.Uname(&uts); err != nil {
+		return ""
+	}
+	return cString(uts.Release[:])
+}
+
+func cString(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,7 @@
+//go:build !linux
+
+package ipset
+
+func kernelVersion() string {
+	return ""
+}
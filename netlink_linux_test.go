@@ -0,0 +1,21 @@
+//go:build linux
+
+package ipset
+
+import "testing"
+
+func TestParseNetlinkCreateOptionsToleratesUnsupportedKeys(t *testing.T) {
+	opts, err := parseNetlinkCreateOptions([]string{
+		"family", FamilyInet,
+		"hashsize", "4096",
+		"netmask", "24",
+		"range", "10.0.0.0-10.0.0.255",
+		"maxelem", "65536",
+	})
+	if err != nil {
+		t.Fatalf("parseNetlinkCreateOptions: %v", err)
+	}
+	if opts.MaxElements != 65536 {
+		t.Errorf("MaxElements = %d, want 65536", opts.MaxElements)
+	}
+}
@@ -0,0 +1,60 @@
+//go:build !linux
+
+package ipset
+
+import "errors"
+
+// ErrNetlinkUnsupported is returned by Netlink's methods on platforms where
+// the netlink ipset backend is not available.
+var ErrNetlinkUnsupported = errors.New("ipset: netlink backend is only supported on linux")
+
+// Netlink is a stub on non-linux platforms; every method returns
+// ErrNetlinkUnsupported.
+type Netlink struct{}
+
+// NewNetlink always fails on non-linux platforms.
+func NewNetlink() (*Netlink, error) {
+	return nil, ErrNetlinkUnsupported
+}
+
+var _ Interface = (*Netlink)(nil)
+
+func (n *Netlink) Create(name string, typ string, options ...string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Add(name string, entry string, options ...string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) AddUnique(name string, entry string, options ...string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Delete(name string, entry string, options ...string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Test(name string, entry string, options ...string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Destroy(name string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Flush(name string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Rename(from string, to string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) Swap(from string, to string) error {
+	return ErrNetlinkUnsupported
+}
+
+func (n *Netlink) List(name string) (*IPSetInfo, error) {
+	return nil, ErrNetlinkUnsupported
+}
@@ -0,0 +1,124 @@
+package ipset
+
+import (
+	"fmt"
+	"sync"
+)
+
+// RefCountedSet wraps an Interface and reference-counts set membership by
+// owner, so that when multiple owners (e.g. two network policies both
+// selecting the same pod) add the same entry to the same set, one owner
+// releasing the entry does not remove it while another owner still needs
+// it. The real ipset del is only issued once the last owner releases an
+// entry.
+type RefCountedSet struct {
+	set Interface
+
+	mu sync.Mutex
+	// owners[setName][entry] holds the owners currently claiming entry in
+	// setName.
+	owners map[string]map[string]map[string]struct{}
+
+	// affectedByDeleteBug reports whether the running kernel is affected by
+	// the ipset del bug described in netfilter bugzilla #1119 (kernel
+	// 4.2-4.10), where a del can remove more than the targeted entry. When
+	// true, DelEntry resyncs the set from tracked state after every delete.
+	affectedByDeleteBug bool
+}
+
+// NewRefCountedSet wraps set with reference-counted membership tracking.
+func NewRefCountedSet(set Interface) *RefCountedSet {
+	return &RefCountedSet{
+		set:                 set,
+		owners:              make(map[string]map[string]map[string]struct{}),
+		affectedByDeleteBug: kernelAffectedByDeleteBug(),
+	}
+}
+
+// AddEntry adds entry to the named set on behalf of owner. The real ipset
+// add is only issued the first time any owner adds entry.
+func (r *RefCountedSet) AddEntry(owner string, name string, entry string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.owners[name]
+	if entries == nil {
+		entries = make(map[string]map[string]struct{})
+		r.owners[name] = entries
+	}
+	owners := entries[entry]
+	first := owners == nil
+	if first {
+		owners = make(map[string]struct{})
+		entries[entry] = owners
+	}
+	owners[owner] = struct{}{}
+
+	if !first {
+		return nil
+	}
+
+	if err := r.set.AddUnique(name, entry); err != nil {
+		delete(owners, owner)
+		if len(owners) == 0 {
+			delete(entries, entry)
+		}
+		return fmt.Errorf("ipset: add %s to %s for owner %s: %w", entry, name, owner, err)
+	}
+	return nil
+}
+
+// DelEntry releases owner's claim on entry in the named set. The real
+// ipset del is only issued once no owner still holds the entry.
+func (r *RefCountedSet) DelEntry(owner string, name string, entry string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := r.owners[name]
+	if entries == nil {
+		return nil
+	}
+	owners := entries[entry]
+	if owners == nil {
+		return nil
+	}
+	delete(owners, owner)
+	if len(owners) > 0 {
+		return nil
+	}
+
+	if err := r.set.Delete(name, entry); err != nil {
+		owners[owner] = struct{}{}
+		return fmt.Errorf("ipset: delete %s from %s for owner %s: %w", entry, name, owner, err)
+	}
+	delete(entries, entry)
+
+	if r.affectedByDeleteBug {
+		return r.resyncLocked(name)
+	}
+	return nil
+}
+
+// Resync reconciles the kernel's view of the named set against the entries
+// this RefCountedSet is still tracking, by flushing the set and re-adding
+// every tracked entry. It exists as a workaround for the kernel 4.2-4.10
+// ipset del bug (netfilter bugzilla #1119); on affected kernels DelEntry
+// calls it automatically, but callers can also invoke it periodically as a
+// safety net.
+func (r *RefCountedSet) Resync(name string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.resyncLocked(name)
+}
+
+func (r *RefCountedSet) resyncLocked(name string) error {
+	if err := r.set.Flush(name); err != nil {
+		return fmt.Errorf("ipset: resync %s: %w", name, err)
+	}
+	for entry := range r.owners[name] {
+		if err := r.set.AddUnique(name, entry); err != nil {
+			return fmt.Errorf("ipset: resync %s: %w", name, err)
+		}
+	}
+	return nil
+}
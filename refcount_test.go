@@ -0,0 +1,48 @@
+package ipset
+
+import (
+	"errors"
+	"testing"
+)
+
+// fakeInterface is a minimal Interface test double for RefCountedSet, where
+// only Delete's behavior needs to be controlled.
+type fakeInterface struct {
+	Interface
+	deleteErr error
+}
+
+func (f *fakeInterface) AddUnique(name string, entry string, options ...string) error {
+	return nil
+}
+
+func (f *fakeInterface) Delete(name string, entry string, options ...string) error {
+	return f.deleteErr
+}
+
+func (f *fakeInterface) Flush(name string) error { return nil }
+
+func TestDelEntryRollsBackOnDeleteError(t *testing.T) {
+	fake := &fakeInterface{deleteErr: errors.New("kernel error")}
+	r := NewRefCountedSet(fake)
+
+	if err := r.AddEntry("owner1", "myset", "10.0.0.1"); err != nil {
+		t.Fatalf("AddEntry: %v", err)
+	}
+
+	if err := r.DelEntry("owner1", "myset", "10.0.0.1"); err == nil {
+		t.Fatal("expected DelEntry to return the kernel error")
+	}
+
+	if _, ok := r.owners["myset"]["10.0.0.1"]["owner1"]; !ok {
+		t.Fatal("owner1's claim on 10.0.0.1 should still be tracked after a failed delete")
+	}
+
+	fake.deleteErr = nil
+	if err := r.DelEntry("owner1", "myset", "10.0.0.1"); err != nil {
+		t.Fatalf("DelEntry after recovery: %v", err)
+	}
+	if _, ok := r.owners["myset"]["10.0.0.1"]; ok {
+		t.Fatal("10.0.0.1 should no longer be tracked once the delete succeeds")
+	}
+}
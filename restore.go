@@ -0,0 +1,128 @@
+package ipset
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Apply pipes script to `ipset restore -exist` as a single stdin stream,
+// applying every command it contains in one process invocation instead of
+// one exec call per entry. This is the fast path for workloads that add or
+// delete thousands of entries. The -exist flag makes restore idempotent:
+// creating a set or adding an entry that already exists is not an error.
+func (set *IPSet) Apply(script io.Reader) error {
+	cmd := set.exec().Command(set.Path, "restore", "-exist")
+	cmd.SetStdin(script)
+
+	var stdout, stderr bytes.Buffer
+	cmd.SetStdout(&stdout)
+	cmd.SetStderr(&stderr)
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ipset restore: %w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// BatchBuilder accumulates ipset restore commands to be applied together
+// with Apply. It does not talk to the kernel itself; call Reader() and pass
+// the result to Apply once the batch is complete.
+type BatchBuilder struct {
+	buf bytes.Buffer
+}
+
+// Create appends a `create` command to the batch.
+func (b *BatchBuilder) Create(name string, typ string, options ...string) {
+	b.writeLine(append([]string{"create", name, typ}, options...)...)
+}
+
+// Add appends an `add` command to the batch.
+func (b *BatchBuilder) Add(name string, entry string, options ...string) {
+	b.writeLine(append([]string{"add", name, entry}, options...)...)
+}
+
+// Delete appends a `del` command to the batch.
+func (b *BatchBuilder) Delete(name string, entry string) {
+	b.writeLine("del", name, entry)
+}
+
+// Flush appends a `flush` command to the batch.
+func (b *BatchBuilder) Flush(name string) {
+	b.writeLine("flush", name)
+}
+
+// Swap appends a `swap` command to the batch.
+func (b *BatchBuilder) Swap(from string, to string) {
+	b.writeLine("swap", from, to)
+}
+
+// Rename appends a `rename` command to the batch.
+func (b *BatchBuilder) Rename(from string, to string) {
+	b.writeLine("rename", from, to)
+}
+
+// Destroy appends a `destroy` command to the batch.
+func (b *BatchBuilder) Destroy(name string) {
+	b.writeLine("destroy", name)
+}
+
+// Reader returns the accumulated script as an io.Reader suitable for Apply.
+func (b *BatchBuilder) Reader() io.Reader {
+	return bytes.NewReader(b.buf.Bytes())
+}
+
+func (b *BatchBuilder) writeLine(args ...string) {
+	b.buf.WriteString(strings.Join(args, " "))
+	b.buf.WriteByte('\n')
+}
+
+// SwapApply atomically replaces the contents of the named set with entries:
+// it creates a temporary set matching the live set's type and header
+// options (family, hashsize, maxelem, timeout, netmask, range, counters,
+// comments, skbinfo), restores entries into it, swaps it with the live
+// set, then destroys the temporary set. Since `ipset swap` exchanges the
+// sets' headers along with their contents, matching these options is what
+// keeps the live set's capabilities (such as per-entry comments or
+// counters) intact across the swap. This avoids any window where the live
+// set is empty or only partially populated, at the cost of one ListSet
+// call to discover the existing set's type and options.
+//
+// SwapApply is not safe to call concurrently for the same name, since the
+// temporary set name is derived from it.
+func (set *IPSet) SwapApply(name string, entries []string) error {
+	info, err := set.ListSet(name)
+	if err != nil {
+		return fmt.Errorf("ipset: swap-apply %s: %w", name, err)
+	}
+
+	tmpName := name + "-tmp"
+	spec := IPSetSpec{
+		Family:   info.Family,
+		HashSize: info.HashSize,
+		MaxElem:  info.MaxElem,
+		Timeout:  info.Timeout,
+		Netmask:  info.Netmask,
+		Range:    info.Range,
+		Counters: info.Counters,
+		Comment:  info.Comments,
+		SkbInfo:  info.SkbInfo,
+	}
+
+	var b BatchBuilder
+	b.Create(tmpName, info.TypeName, createOptions(spec, false)...)
+	for _, entry := range entries {
+		b.Add(tmpName, entry)
+	}
+
+	if err := set.Apply(b.Reader()); err != nil {
+		return fmt.Errorf("ipset: swap-apply %s: %w", name, err)
+	}
+
+	if err := set.Swap(name, tmpName); err != nil {
+		return fmt.Errorf("ipset: swap-apply %s: %w", name, err)
+	}
+
+	return set.Destroy(tmpName)
+}
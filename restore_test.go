@@ -0,0 +1,61 @@
+package ipset
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSwapApplyMatchesLiveSetOptions(t *testing.T) {
+	exec := &fakeExec{
+		cmds: []*fakeCmd{
+			{stdout: `Name: v6set
+Type: hash:ip
+Revision: 4
+Header: family inet6 hashsize 1024 maxelem 65536 timeout 300 netmask 64 counters comment skbinfo
+Size in memory: 88
+References: 0
+Number of entries: 0
+Members:
+`}, // list
+			{}, // restore
+			{}, // swap
+			{}, // destroy
+		},
+	}
+	set := &IPSet{Path: "ipset", Exec: exec}
+
+	if err := set.SwapApply("v6set", []string{"2001:db8::1"}); err != nil {
+		t.Fatalf("SwapApply: %v", err)
+	}
+
+	if len(exec.calls) != 4 {
+		t.Fatalf("expected 4 commands, got %d: %v", len(exec.calls), exec.calls)
+	}
+
+	restoreScript := string(exec.cmds[1].stdin)
+	createLine := strings.Split(restoreScript, "\n")[0]
+	for _, want := range []string{"family inet6", "hashsize 1024", "maxelem 65536", "timeout 300", "netmask 64", "counters", "comment", "skbinfo"} {
+		if !strings.Contains(createLine, want) {
+			t.Errorf("temp set create line %q missing %q", createLine, want)
+		}
+	}
+
+	if got, want := exec.calls[2], []string{"swap", "v6set", "v6set-tmp"}; !equalStrings(got, want) {
+		t.Errorf("swap args = %v, want %v", got, want)
+	}
+	if got, want := exec.calls[3], []string{"destroy", "v6set-tmp"}; !equalStrings(got, want) {
+		t.Errorf("destroy args = %v, want %v", got, want)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package ipset
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SetType identifies the storage method and data type of a set, as passed
+// to `ipset create`. See http://ipset.netfilter.org/ipset.man.html#lbAT for
+// the full list supported by the kernel.
+type SetType string
+
+const (
+	HashIP        SetType = "hash:ip"
+	HashNet       SetType = "hash:net"
+	HashIPPort    SetType = "hash:ip,port"
+	HashIPPortIP  SetType = "hash:ip,port,ip"
+	HashIPPortNet SetType = "hash:ip,port,net"
+	HashNetPort   SetType = "hash:net,port"
+	HashMAC       SetType = "hash:mac"
+	BitmapIP      SetType = "bitmap:ip"
+	BitmapIPMAC   SetType = "bitmap:ip,mac"
+	BitmapPort    SetType = "bitmap:port"
+	ListSet       SetType = "list:set"
+)
+
+// IPSetSpec describes a set to be created with CreateSet. Zero-valued
+// fields are omitted from the generated `ipset create` invocation, so the
+// kernel's own defaults apply.
+type IPSetSpec struct {
+	Name     string
+	Type     SetType
+	Family   string
+	HashSize uint32
+	MaxElem  uint32
+	Timeout  uint32
+	Comment  bool
+	Counters bool
+	SkbInfo  bool
+	Netmask  uint8
+	Range    string
+}
+
+// CreateSet creates a new ipset from the given spec. If ignoreExist is
+// true, an existing set of the same name and type is left untouched
+// instead of returning an error (the `-exist` flag).
+func (set *IPSet) CreateSet(spec IPSetSpec, ignoreExist bool) error {
+	return set.Create(spec.Name, string(spec.Type), createOptions(spec, ignoreExist)...)
+}
+
+// createOptions translates an IPSetSpec into the options accepted by
+// (*IPSet).Create: key, value pairs, followed by the bare flags counters,
+// comment, skbinfo and -exist where set. It is also used by SwapApply to
+// recreate a temporary set matching an existing one.
+func createOptions(spec IPSetSpec, ignoreExist bool) []string {
+	var options []string
+
+	if spec.Family != "" {
+		options = append(options, "family", spec.Family)
+	}
+	if spec.HashSize != 0 {
+		options = append(options, "hashsize", strconv.FormatUint(uint64(spec.HashSize), 10))
+	}
+	if spec.MaxElem != 0 {
+		options = append(options, "maxelem", strconv.FormatUint(uint64(spec.MaxElem), 10))
+	}
+	if spec.Timeout != 0 {
+		options = append(options, "timeout", strconv.FormatUint(uint64(spec.Timeout), 10))
+	}
+	if spec.Netmask != 0 {
+		options = append(options, "netmask", strconv.FormatUint(uint64(spec.Netmask), 10))
+	}
+	if spec.Range != "" {
+		options = append(options, "range", spec.Range)
+	}
+	if spec.Counters {
+		options = append(options, "counters")
+	}
+	if spec.Comment {
+		options = append(options, "comment")
+	}
+	if spec.SkbInfo {
+		options = append(options, "skbinfo")
+	}
+	if ignoreExist {
+		options = append(options, "-exist")
+	}
+
+	return options
+}
+
+// ListSet returns the structured result of `ipset list <name>`: its header
+// fields plus every member entry, with per-entry options such as timeout,
+// packets, bytes and comment parsed out.
+func (set *IPSet) ListSet(name string) (*IPSetInfo, error) {
+	out, err := set.run("list", name)
+	if err != nil {
+		return nil, err
+	}
+	return parseListOutput(out.String())
+}
+
+func parseListOutput(output string) (*IPSetInfo, error) {
+	info := &IPSetInfo{}
+	lines := strings.Split(output, "\n")
+
+	membersAt := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "Members:" {
+			membersAt = i
+			break
+		}
+
+		switch {
+		case strings.HasPrefix(line, "Name:"):
+			info.SetName = strings.TrimSpace(strings.TrimPrefix(line, "Name:"))
+		case strings.HasPrefix(line, "Type:"):
+			info.TypeName = strings.TrimSpace(strings.TrimPrefix(line, "Type:"))
+		case strings.HasPrefix(line, "Revision:"):
+			v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Revision:")), 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("parse revision: %w", err)
+			}
+			info.Revision = uint8(v)
+		case strings.HasPrefix(line, "Header:"):
+			if err := parseHeader(strings.TrimSpace(strings.TrimPrefix(line, "Header:")), info); err != nil {
+				return nil, err
+			}
+		case strings.HasPrefix(line, "Size in memory:"):
+			v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Size in memory:")), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("parse size in memory: %w", err)
+			}
+			info.MemSize = uint32(v)
+		case strings.HasPrefix(line, "References:"):
+			v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "References:")), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("parse references: %w", err)
+			}
+			info.References = uint32(v)
+		case strings.HasPrefix(line, "Number of entries:"):
+			v, err := strconv.ParseUint(strings.TrimSpace(strings.TrimPrefix(line, "Number of entries:")), 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("parse number of entries: %w", err)
+			}
+			info.NumEntries = uint32(v)
+		}
+	}
+
+	if membersAt < 0 {
+		return info, nil
+	}
+
+	for _, line := range lines[membersAt+1:] {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		entry, err := parseMemberLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("parse member %q: %w", line, err)
+		}
+		info.Entries = append(info.Entries, entry)
+	}
+
+	return info, nil
+}
+
+// parseHeader parses the space-separated key/value and boolean flag pairs
+// on the "Header:" line of `ipset list` output, e.g.
+// "family inet hashsize 1024 maxelem 65536 timeout 300 counters".
+func parseHeader(header string, info *IPSetInfo) error {
+	fields := strings.Fields(header)
+	for i := 0; i < len(fields); i++ {
+		switch fields[i] {
+		case "family":
+			i++
+			info.Family = fields[i]
+		case "hashsize":
+			i++
+			v, err := strconv.ParseUint(fields[i], 10, 32)
+			if err != nil {
+				return fmt.Errorf("parse hashsize: %w", err)
+			}
+			info.HashSize = uint32(v)
+		case "maxelem":
+			i++
+			v, err := strconv.ParseUint(fields[i], 10, 32)
+			if err != nil {
+				return fmt.Errorf("parse maxelem: %w", err)
+			}
+			info.MaxElem = uint32(v)
+		case "timeout":
+			i++
+			v, err := strconv.ParseUint(fields[i], 10, 32)
+			if err != nil {
+				return fmt.Errorf("parse timeout: %w", err)
+			}
+			info.Timeout = uint32(v)
+		case "netmask":
+			i++
+			v, err := strconv.ParseUint(fields[i], 10, 8)
+			if err != nil {
+				return fmt.Errorf("parse netmask: %w", err)
+			}
+			info.Netmask = uint8(v)
+		case "range":
+			i++
+			info.Range = fields[i]
+		case "markmask", "size", "bucketsize", "initval", "probes", "resize":
+			// recognized but not yet modeled on IPSetInfo.
+			i++
+		case "counters":
+			info.Counters = true
+		case "comment":
+			info.Comments = true
+		case "skbinfo":
+			info.SkbInfo = true
+		case "forceadd":
+			// boolean flag; nothing further to parse.
+		default:
+			// Unknown field from a newer ipset/kernel version. Tolerate it
+			// instead of failing the whole parse; at worst its value is
+			// skipped too, since it won't match a known key either.
+		}
+	}
+	return nil
+}
+
+// parseMemberLine parses one line under "Members:" in `ipset list` output,
+// e.g. `192.168.1.1 comment "a host" timeout 290 packets 5 bytes 420`.
+func parseMemberLine(line string) (IPSetEntry, error) {
+	var comment string
+	if idx := strings.Index(line, `comment "`); idx >= 0 {
+		rest := line[idx+len(`comment "`):]
+		end := strings.Index(rest, `"`)
+		if end < 0 {
+			return IPSetEntry{}, fmt.Errorf("unterminated comment")
+		}
+		comment = rest[:end]
+		line = line[:idx] + rest[end+1:]
+	}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return IPSetEntry{}, fmt.Errorf("empty entry")
+	}
+
+	// parseEntry only understands IP-based member formats (hash:ip,
+	// hash:net and their ,port/,ip/,net variants). Other set types, such
+	// as hash:mac, bitmap:port and list:set, have members that aren't an
+	// IP at all; for those, fall back to keeping just the raw string
+	// rather than failing the whole list.
+	entry, err := parseEntry(fields[0])
+	if err != nil {
+		entry = IPSetEntry{}
+	}
+	entry.Entry = fields[0]
+	entry.Comment = comment
+
+	for i := 1; i < len(fields); i++ {
+		key := fields[i]
+		if key == "nomatch" {
+			// Boolean flag on hash:net-like entries; no value follows.
+			continue
+		}
+		if i+1 >= len(fields) {
+			return IPSetEntry{}, fmt.Errorf("option %q has no value", key)
+		}
+		value := fields[i+1]
+		i++
+		switch key {
+		case "timeout":
+			v, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return IPSetEntry{}, fmt.Errorf("parse timeout: %w", err)
+			}
+			entry.Timeout = uint32(v)
+		case "packets":
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return IPSetEntry{}, fmt.Errorf("parse packets: %w", err)
+			}
+			entry.Packets = v
+		case "bytes":
+			v, err := strconv.ParseUint(value, 10, 64)
+			if err != nil {
+				return IPSetEntry{}, fmt.Errorf("parse bytes: %w", err)
+			}
+			entry.Bytes = v
+		case "skbmark", "skbprio", "skbqueue":
+			// Recognized but not yet modeled on IPSetEntry.
+		default:
+			return IPSetEntry{}, fmt.Errorf("unsupported entry option %q", key)
+		}
+	}
+
+	return entry, nil
+}
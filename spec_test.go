@@ -0,0 +1,226 @@
+package ipset
+
+import (
+	"testing"
+)
+
+func TestParseListOutput(t *testing.T) {
+	tests := []struct {
+		name    string
+		output  string
+		want    *IPSetInfo
+		wantErr bool
+	}{
+		{
+			name: "hash:ip with counters and comment",
+			output: `Name: myset
+Type: hash:ip
+Revision: 4
+Header: family inet hashsize 1024 maxelem 65536 counters comment
+Size in memory: 128
+References: 0
+Number of entries: 2
+Members:
+10.0.0.1 comment "a host" timeout 290 packets 5 bytes 420
+10.0.0.2 timeout 100
+`,
+			want: &IPSetInfo{
+				SetName:    "myset",
+				TypeName:   "hash:ip",
+				Revision:   4,
+				Family:     "inet",
+				HashSize:   1024,
+				MaxElem:    65536,
+				MemSize:    128,
+				References: 0,
+				NumEntries: 2,
+				Counters:   true,
+				Comments:   true,
+				Entries: []IPSetEntry{
+					{Entry: "10.0.0.1", IP: mustParseIP("10.0.0.1"), Comment: "a host", Timeout: 290, Packets: 5, Bytes: 420},
+					{Entry: "10.0.0.2", IP: mustParseIP("10.0.0.2"), Timeout: 100},
+				},
+			},
+		},
+		{
+			name: "header field from a newer kernel is tolerated",
+			output: `Name: v6set
+Type: hash:ip
+Revision: 4
+Header: family inet6 hashsize 1024 maxelem 65536 bucketsize 12 initval 0x1a2b3c4d
+Size in memory: 88
+References: 0
+Number of entries: 0
+Members:
+`,
+			want: &IPSetInfo{
+				SetName:  "v6set",
+				TypeName: "hash:ip",
+				Revision: 4,
+				Family:   "inet6",
+				HashSize: 1024,
+				MaxElem:  65536,
+				MemSize:  88,
+			},
+		},
+		{
+			name: "nomatch boolean member flag",
+			output: `Name: netset
+Type: hash:net
+Revision: 6
+Header: family inet hashsize 1024 maxelem 65536
+Size in memory: 88
+References: 0
+Number of entries: 1
+Members:
+10.0.0.0/24 nomatch timeout 290
+`,
+			want: &IPSetInfo{
+				SetName:    "netset",
+				TypeName:   "hash:net",
+				Revision:   6,
+				Family:     "inet",
+				HashSize:   1024,
+				MaxElem:    65536,
+				MemSize:    88,
+				NumEntries: 1,
+				Entries: []IPSetEntry{
+					{Entry: "10.0.0.0/24", IP: mustParseIP("10.0.0.0"), CIDR: 24, Timeout: 290},
+				},
+			},
+		},
+		{
+			name: "netmask, range and skbinfo header fields",
+			output: `Name: skbset
+Type: hash:ip
+Revision: 4
+Header: family inet netmask 24 range 10.0.0.0-10.0.0.255 skbinfo
+Size in memory: 88
+References: 0
+Number of entries: 0
+Members:
+`,
+			want: &IPSetInfo{
+				SetName:  "skbset",
+				TypeName: "hash:ip",
+				Revision: 4,
+				Family:   "inet",
+				MemSize:  88,
+				Netmask:  24,
+				Range:    "10.0.0.0-10.0.0.255",
+				SkbInfo:  true,
+			},
+		},
+		{
+			name: "unsupported member option errors",
+			output: `Name: myset
+Type: hash:ip
+Revision: 4
+Header: family inet
+Size in memory: 88
+References: 0
+Number of entries: 1
+Members:
+10.0.0.1 bogus 1
+`,
+			wantErr: true,
+		},
+		{
+			name: "hash:mac members are not IPs but still list",
+			output: `Name: macset
+Type: hash:mac
+Revision: 1
+Header: hashsize 1024 maxelem 65536
+Size in memory: 88
+References: 0
+Number of entries: 2
+Members:
+00:11:22:33:44:55 timeout 290
+aa:bb:cc:dd:ee:ff
+`,
+			want: &IPSetInfo{
+				SetName:    "macset",
+				TypeName:   "hash:mac",
+				Revision:   1,
+				HashSize:   1024,
+				MaxElem:    65536,
+				MemSize:    88,
+				NumEntries: 2,
+				Entries: []IPSetEntry{
+					{Entry: "00:11:22:33:44:55", Timeout: 290},
+					{Entry: "aa:bb:cc:dd:ee:ff"},
+				},
+			},
+		},
+		{
+			name: "bitmap:port members are bare port numbers",
+			output: `Name: portset
+Type: bitmap:port
+Revision: 3
+Header: range 80-443
+Size in memory: 88
+References: 0
+Number of entries: 1
+Members:
+80
+`,
+			want: &IPSetInfo{
+				SetName:    "portset",
+				TypeName:   "bitmap:port",
+				Revision:   3,
+				Range:      "80-443",
+				MemSize:    88,
+				NumEntries: 1,
+				Entries: []IPSetEntry{
+					{Entry: "80"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseListOutput(tt.output)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseListOutput: %v", err)
+			}
+			if !infoEqual(got, tt.want) {
+				t.Errorf("parseListOutput() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func infoEqual(a, b *IPSetInfo) bool {
+	if a.SetName != b.SetName || a.TypeName != b.TypeName || a.Family != b.Family ||
+		a.Revision != b.Revision || a.HashSize != b.HashSize || a.MaxElem != b.MaxElem ||
+		a.MemSize != b.MemSize || a.References != b.References || a.NumEntries != b.NumEntries ||
+		a.Netmask != b.Netmask || a.Range != b.Range ||
+		a.Counters != b.Counters || a.Comments != b.Comments || a.SkbInfo != b.SkbInfo {
+		return false
+	}
+	if len(a.Entries) != len(b.Entries) {
+		return false
+	}
+	for i := range a.Entries {
+		ea, eb := a.Entries[i], b.Entries[i]
+		eaIP, ebIP := "", ""
+		if ea.IP != nil {
+			eaIP = ea.IP.String()
+		}
+		if eb.IP != nil {
+			ebIP = eb.IP.String()
+		}
+		if ea.Entry != eb.Entry || eaIP != ebIP || ea.CIDR != eb.CIDR || ea.Comment != eb.Comment ||
+			ea.Timeout != eb.Timeout || ea.Packets != eb.Packets || ea.Bytes != eb.Bytes {
+			return false
+		}
+	}
+	return true
+}